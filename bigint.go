@@ -0,0 +1,43 @@
+package ranksel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+)
+
+// NewBitVectorFromBigInt creates a vector whose ith bit is
+// the ith bit of n. The vector length is n.BitLen() so the
+// last bit of a nonzero n is always set. Panics if n is
+// negative.
+func NewBitVectorFromBigInt(n *big.Int, opts *Options) *BitVector {
+	if n.Sign() < 0 {
+		panic("ranksel: big.Int must not be negative")
+	}
+
+	// Convert big-endian bytes to little-endian
+	data := n.Bytes()
+	for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+		data[i], data[j] = data[j], data[i]
+	}
+
+	vec, err := ReadBitVectorBytes(bytes.NewReader(data), n.BitLen(), opts)
+	if err != nil {
+		panic(err)
+	}
+
+	return vec
+}
+
+// BigInt returns a big.Int whose ith bit is the ith bit of
+// the vector. Trailing 0s of the vector are not preserved
+// since they do not change the value of the integer.
+func (v *BitVector) BigInt() *big.Int {
+	words := v.Words()
+	data := make([]byte, len(words)*8)
+	for i, w := range words {
+		binary.BigEndian.PutUint64(data[len(data)-(i+1)*8:], w)
+	}
+
+	return new(big.Int).SetBytes(data)
+}
@@ -0,0 +1,33 @@
+package ranksel
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBigInt(t *testing.T) {
+	for _, nbits := range []int{0, 1, 8, 63, 64, 65, 1000, 12345} {
+		n := new(big.Int).Rand(rand.New(rand.NewSource(int64(nbits))),
+			new(big.Int).Lsh(big.NewInt(1), uint(nbits)))
+
+		vec := NewBitVectorFromBigInt(n, nil)
+		assert.Equal(t, n.BitLen(), vec.Len())
+		for i := 0; i < vec.Len(); i++ {
+			if !assert.EqualValues(t, n.Bit(i), vec.Bit(i)) {
+				break
+			}
+		}
+
+		assert.Equal(t, 0, n.Cmp(vec.BigInt()))
+	}
+
+	// Trailing 0s are dropped
+	vec := NewBitVector(nil)
+	vec.Add(0x5, 16)
+	assert.Equal(t, 0, big.NewInt(5).Cmp(vec.BigInt()))
+
+	assert.Panics(t, func() { NewBitVectorFromBigInt(big.NewInt(-1), nil) })
+}
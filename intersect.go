@@ -0,0 +1,108 @@
+package ranksel
+
+import "github.com/robskie/bit"
+
+// OverlapByBlock returns the number of indices in each block of
+// blockBits bits where both this vector and other have a set bit.
+// The kth element covers the interval [k*blockBits, (k+1)*blockBits)
+// and only the indices that are inside both vectors are counted,
+// so the last block may be shorter. Panics if blockBits is not
+// positive.
+func (v *BitVector) OverlapByBlock(other *BitVector, blockBits int) []int {
+	if blockBits <= 0 {
+		panic("ranksel: block size must be greater than 0")
+	}
+
+	length := v.bits.Len()
+	if other.bits.Len() < length {
+		length = other.bits.Len()
+	}
+
+	a := v.bits.Bits()
+	b := other.bits.Bits()
+	counts := make([]int, (length+blockBits-1)/blockBits)
+	for k := range counts {
+		i := k * blockBits
+		j := i + blockBits
+		if j > length {
+			j = length
+		}
+
+		counts[k] = andCountRange(a, b, i, j)
+	}
+
+	return counts
+}
+
+// ValidRank1 counts the number of indices from the beginning
+// up to the ith index where both this vector and validity have
+// a set bit. This is the rank of the 1s of a nullable column
+// whose non-null entries are marked in validity. Panics if i
+// is outside either vector.
+func (v *BitVector) ValidRank1(i int, validity *BitVector) int {
+	if i >= v.bits.Len() || i >= validity.bits.Len() || i < 0 {
+		panic("ranksel: index out of range")
+	}
+
+	return andCountRange(v.bits.Bits(), validity.bits.Bits(), 0, i+1)
+}
+
+// ValidSelect1 returns the index of the ith index where both
+// this vector and validity have a set bit. This scans the words
+// of both vectors from the beginning. Panics if i is zero or
+// greater than the number of such indices.
+func (v *BitVector) ValidSelect1(i int, validity *BitVector) int {
+	if i == 0 {
+		panic("ranksel: input must be greater than 0")
+	}
+
+	length := v.bits.Len()
+	if validity.bits.Len() < length {
+		length = validity.bits.Len()
+	}
+
+	a := v.bits.Bits()
+	b := validity.bits.Bits()
+	rank := 0
+	for k := 0; k<<6 < length; k++ {
+		w := a[k] & b[k]
+		if rem := length - (k << 6); rem < 64 {
+			w &= (1 << uint(rem)) - 1
+		}
+
+		popcnt := bit.PopCount(w)
+		if rank+popcnt >= i {
+			return (k << 6) + bit.Select(w, i-rank)
+		}
+		rank += popcnt
+	}
+
+	panic("ranksel: input exceeds number of valid 1s")
+}
+
+// andCountRange counts the number of indices in
+// the interval [i, j) where both a and b are set.
+func andCountRange(a, b []uint64, i, j int) int {
+	if i >= j {
+		return 0
+	}
+
+	lo := i >> 6
+	hi := j >> 6
+	if lo == hi {
+		w := (a[lo] & b[lo]) >> uint(i&63)
+		return bit.PopCount(w & ((1 << uint(j-i)) - 1))
+	}
+
+	count := bit.PopCount((a[lo] & b[lo]) >> uint(i&63))
+	for k := lo + 1; k < hi; k++ {
+		count += bit.PopCount(a[k] & b[k])
+	}
+
+	if j&63 != 0 {
+		w := a[hi] & b[hi]
+		count += bit.PopCount(w & ((1 << uint(j&63)) - 1))
+	}
+
+	return count
+}
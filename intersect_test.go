@@ -0,0 +1,84 @@
+package ranksel
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverlapByBlock(t *testing.T) {
+	vec1 := NewBitVector(nil)
+	vec2 := NewBitVector(nil)
+	for i := 0; i < 1e4; i++ {
+		vec1.Add(uint64(rand.Intn(2)), 1)
+	}
+	for i := 0; i < 1e4-37; i++ {
+		vec2.Add(uint64(rand.Intn(2)), 1)
+	}
+
+	for _, bs := range []int{1, 50, 64, 1000, 2e4} {
+		counts := vec1.OverlapByBlock(vec2, bs)
+		if !assert.Equal(t, (vec2.Len()+bs-1)/bs, len(counts)) {
+			continue
+		}
+
+		for k, c := range counts {
+			expected := 0
+			for i := k * bs; i < (k+1)*bs && i < vec2.Len(); i++ {
+				expected += int(vec1.Bit(i) & vec2.Bit(i))
+			}
+
+			if !assert.Equal(t, expected, c) {
+				break
+			}
+		}
+	}
+
+	assert.Equal(t, []int{}, vec1.OverlapByBlock(NewBitVector(nil), 64))
+	assert.Panics(t, func() { vec1.OverlapByBlock(vec2, 0) })
+}
+
+func TestValidRank1(t *testing.T) {
+	data := NewBitVector(nil)
+	validity := NewBitVector(nil)
+	for i := 0; i < 1e4; i++ {
+		data.Add(uint64(rand.Intn(2)), 1)
+		validity.Add(uint64(rand.Intn(2)), 1)
+	}
+
+	rank := 0
+	for i := 0; i < data.Len(); i++ {
+		rank += int(data.Bit(i) & validity.Bit(i))
+		if !assert.Equal(t, rank, data.ValidRank1(i, validity)) {
+			break
+		}
+	}
+
+	assert.Panics(t, func() { data.ValidRank1(data.Len(), validity) })
+	assert.Panics(t, func() { data.ValidRank1(0, NewBitVector(nil)) })
+}
+
+func TestValidSelect1(t *testing.T) {
+	data := NewBitVector(nil)
+	validity := NewBitVector(nil)
+	and := NewBitVector(nil)
+	for i := 0; i < 1e4; i++ {
+		d := uint64(rand.Intn(2))
+		v := uint64(rand.Intn(2))
+
+		data.Add(d, 1)
+		validity.Add(v, 1)
+		and.Add(d&v, 1)
+	}
+
+	for i := 1; i <= and.PopCount(); i++ {
+		if !assert.Equal(t, and.Select1(i), data.ValidSelect1(i, validity)) {
+			break
+		}
+	}
+
+	n := and.PopCount()
+	assert.Panics(t, func() { data.ValidSelect1(0, validity) })
+	assert.Panics(t, func() { data.ValidSelect1(n+1, validity) })
+}
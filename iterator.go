@@ -0,0 +1,76 @@
+package ranksel
+
+import "github.com/robskie/bit"
+
+// OnesIterator walks the positions of the set bits
+// of a BitVector in increasing order. It keeps its
+// own scan state so several iterators can be used
+// concurrently over the same vector as long as the
+// vector is not modified.
+type OnesIterator struct {
+	bits   []uint64
+	length int
+
+	// word holds the bits of bits[widx]
+	// that have not been visited yet.
+	word uint64
+	widx int
+}
+
+// Ones returns an iterator positioned before the first
+// set bit of the vector. Adding bits to the vector after
+// creating the iterator invalidates the iterator.
+func (v *BitVector) Ones() *OnesIterator {
+	it := &OnesIterator{
+		bits:   v.bits.Bits(),
+		length: v.bits.Len(),
+	}
+	it.Seek(0)
+
+	return it
+}
+
+// Next returns the index of the next set bit.
+// ok is false if there are no more set bits.
+func (it *OnesIterator) Next() (idx int, ok bool) {
+	for it.word == 0 {
+		it.widx++
+		if it.widx<<6 >= it.length {
+			it.widx = it.length >> 6
+			return -1, false
+		}
+		it.load(it.widx)
+	}
+
+	idx = (it.widx << 6) + bit.Select(it.word, 1)
+	it.word &= it.word - 1
+
+	return idx, true
+}
+
+// Seek moves the iterator so that the next call to
+// Next returns the first set bit at index i or later.
+func (it *OnesIterator) Seek(i int) {
+	if i < 0 {
+		i = 0
+	}
+
+	if i >= it.length {
+		it.widx = it.length >> 6
+		it.word = 0
+		return
+	}
+
+	it.widx = i >> 6
+	it.load(it.widx)
+	it.word &= ^uint64(0) << uint(i&63)
+}
+
+// load sets the current word to bits[widx],
+// clearing the bits that are beyond the length.
+func (it *OnesIterator) load(widx int) {
+	it.word = it.bits[widx]
+	if rem := it.length - (widx << 6); rem < 64 {
+		it.word &= (1 << uint(rem)) - 1
+	}
+}
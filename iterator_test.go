@@ -0,0 +1,101 @@
+package ranksel
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnesIterator(t *testing.T) {
+	vec := NewBitVector(nil)
+	ones := []int{}
+	for i := 0; i < 1e5+13; i++ {
+		b := rand.Intn(4) == 0
+		if b {
+			vec.Add(1, 1)
+			ones = append(ones, i)
+		} else {
+			vec.Add(0, 1)
+		}
+	}
+
+	it := vec.Ones()
+	for _, idx := range ones {
+		i, ok := it.Next()
+		if !assert.True(t, ok) || !assert.Equal(t, idx, i) {
+			break
+		}
+	}
+
+	_, ok := it.Next()
+	assert.False(t, ok)
+	_, ok = it.Next()
+	assert.False(t, ok)
+}
+
+func TestOnesIteratorSeek(t *testing.T) {
+	vec := NewBitVector(nil)
+	ones := []int{}
+	for i := 0; i < 1e4; i++ {
+		if rand.Intn(16) == 0 {
+			vec.Add(1, 1)
+			ones = append(ones, i)
+		} else {
+			vec.Add(0, 1)
+		}
+	}
+
+	it := vec.Ones()
+	for i := 0; i < 1e3; i++ {
+		pos := rand.Intn(vec.Len())
+		it.Seek(pos)
+
+		// Find expected position
+		expected := -1
+		for _, idx := range ones {
+			if idx >= pos {
+				expected = idx
+				break
+			}
+		}
+
+		idx, ok := it.Next()
+		if !assert.Equal(t, expected, idx) ||
+			!assert.Equal(t, expected != -1, ok) {
+			break
+		}
+	}
+
+	it.Seek(vec.Len())
+	_, ok := it.Next()
+	assert.False(t, ok)
+
+	it.Seek(-1)
+	idx, ok := it.Next()
+	assert.True(t, ok)
+	assert.Equal(t, ones[0], idx)
+}
+
+func TestOnesIteratorEmpty(t *testing.T) {
+	vec := NewBitVector(nil)
+	_, ok := vec.Ones().Next()
+	assert.False(t, ok)
+
+	vec.Add(0, 64)
+	_, ok = vec.Ones().Next()
+	assert.False(t, ok)
+}
+
+func BenchmarkOnesIterator(b *testing.B) {
+	initBigVector()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		it := bigVector.Ones()
+		it.Seek(rand.Intn(bigVector.Len()))
+		for j := 0; j < 64; j++ {
+			it.Next()
+		}
+	}
+}
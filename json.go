@@ -0,0 +1,97 @@
+package ranksel
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonVector is the JSON representation of a BitVector.
+// Bits holds the first ceil(Len/8) bytes of the little
+// endian words of the vector encoded in base64.
+type jsonVector struct {
+	Len      int          `json:"len"`
+	PopCount int          `json:"popcount"`
+	Options  *jsonOptions `json:"options,omitempty"`
+	Bits     string       `json:"bits"`
+}
+
+type jsonOptions struct {
+	Sr              int  `json:"sr"`
+	Ss              int  `json:"ss"`
+	Select0Sampling bool `json:"select0Sampling,omitempty"`
+	NoSelectIndex   bool `json:"noSelectIndex,omitempty"`
+}
+
+// MarshalJSON encodes this vector into a JSON object
+// containing the length, popcount, options and the
+// bits encoded in base64.
+func (v *BitVector) MarshalJSON() ([]byte, error) {
+	words := v.Words()
+	data := make([]byte, len(words)*8)
+	for i, w := range words {
+		binary.LittleEndian.PutUint64(data[i*8:], w)
+	}
+
+	return json.Marshal(jsonVector{
+		Len:      v.bits.Len(),
+		PopCount: v.popcount,
+		Options: &jsonOptions{
+			Sr:              v.opts.Sr,
+			Ss:              v.opts.Ss,
+			Select0Sampling: v.opts.Select0Sampling,
+			NoSelectIndex:   v.opts.NoSelectIndex,
+		},
+		Bits: base64.StdEncoding.EncodeToString(data[:(v.bits.Len()+7)>>3]),
+	})
+}
+
+// UnmarshalJSON populates this vector from a JSON object
+// created by MarshalJSON and rebuilds the samples. The
+// default options are used if options are not present.
+func (v *BitVector) UnmarshalJSON(data []byte) error {
+	v.checkFrozen()
+	jv := jsonVector{}
+	if err := json.Unmarshal(data, &jv); err != nil {
+		return fmt.Errorf("ranksel: decode failed (%v)", err)
+	}
+
+	opts := NewOptions()
+	if jo := jv.Options; jo != nil {
+		// Rank1 assumes that Sr is a multiple of 64
+		if jo.Sr <= 0 || jo.Sr%64 != 0 || jo.Ss <= 0 {
+			return fmt.Errorf("ranksel: decode failed (invalid options)")
+		}
+
+		opts.Sr = jo.Sr
+		opts.Ss = jo.Ss
+		opts.Select0Sampling = jo.Select0Sampling
+		opts.NoSelectIndex = jo.NoSelectIndex
+	}
+
+	bits, err := base64.StdEncoding.DecodeString(jv.Bits)
+	if err != nil {
+		return fmt.Errorf("ranksel: decode failed (%v)", err)
+	} else if len(bits) != (jv.Len+7)>>3 {
+		return fmt.Errorf("ranksel: decode failed (length mismatch)")
+	}
+
+	nv, err := ReadBitVectorBytes(bytes.NewReader(bits), jv.Len, opts)
+	if err != nil {
+		return err
+	} else if nv.popcount != jv.PopCount {
+		return fmt.Errorf("ranksel: decode failed (popcount mismatch)")
+	}
+
+	v.bits = nv.bits
+	v.ranks = nv.ranks
+	v.indices = nv.indices
+	v.indices0 = nv.indices0
+	v.popcount = nv.popcount
+	v.opts = nv.opts
+	v.complement = nil
+
+	return nil
+}
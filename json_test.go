@@ -0,0 +1,66 @@
+package ranksel
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSON(t *testing.T) {
+	type document struct {
+		Name string     `json:"name"`
+		Vec  *BitVector `json:"vec"`
+	}
+
+	opts := &Options{Sr: 512, Ss: 1024, Select0Sampling: true}
+	for _, nbits := range []int{0, 1, 63, 64, 1e4 + 5} {
+		vec := NewRandomBitVector(nbits, 0.5, 1, opts)
+		data, err := json.Marshal(document{"doc", vec})
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		doc := document{}
+		err = json.Unmarshal(data, &doc)
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		nvec := doc.Vec
+		assert.Equal(t, "doc", doc.Name)
+		assert.Equal(t, opts, nvec.opts)
+		assert.Equal(t, vec.Words(), nvec.Words())
+		assert.Equal(t, vec.ranks, nvec.ranks)
+		assert.Equal(t, vec.indices, nvec.indices)
+		assert.Equal(t, vec.indices0, nvec.indices0)
+		assert.Equal(t, vec.popcount, nvec.popcount)
+	}
+}
+
+func TestJSONDefaultOptions(t *testing.T) {
+	vec := NewBitVector(nil)
+	err := json.Unmarshal([]byte(`{"len":10,"popcount":2,"bits":"BQA="}`), vec)
+	if assert.NoError(t, err) {
+		assert.Equal(t, NewOptions(), vec.opts)
+		assert.Equal(t, 10, vec.Len())
+		assert.Equal(t, 0, vec.Select1(1))
+		assert.Equal(t, 2, vec.Select1(2))
+	}
+}
+
+func TestJSONInvalid(t *testing.T) {
+	inputs := []string{
+		`{"len":10,"popcount":3,"bits":"BQA="}`,
+		`{"len":17,"popcount":2,"bits":"BQA="}`,
+		`{"len":10,"popcount":2,"bits":"B!I="}`,
+		`{"len":10,"popcount":2,"options":{"sr":0,"ss":0},"bits":"BQA="}`,
+		`{"len":10,"popcount":2,"options":{"sr":100,"ss":64},"bits":"BQA="}`,
+		`[]`,
+	}
+
+	for _, in := range inputs {
+		vec := NewBitVector(nil)
+		assert.Error(t, json.Unmarshal([]byte(in), vec), in)
+	}
+}
@@ -0,0 +1,62 @@
+package ranksel
+
+// NewQuotientVector splits a non-decreasing sequence of values
+// into quotients and remainders as used by quotient filters and
+// Elias-Fano codes. The quotient of each value is its upper bits
+// (value >> width) and is stored in unary in the returned vector:
+// the ith value is represented by a 1 that is preceded by as many
+// 0s as the difference between its quotient and the previous one.
+// The remainders are the lower width bits of each value and are
+// returned in a separate slice. Panics if width is not in range
+// [0,63] or if the values are not sorted.
+func NewQuotientVector(values []uint64, width int, opts *Options) (*BitVector, []uint64) {
+	if width < 0 || width > 63 {
+		panic("ranksel: remainder width must be in range [0,63]")
+	}
+
+	vec := NewBitVector(opts)
+	rems := make([]uint64, len(values))
+	mask := (uint64(1) << uint(width)) - 1
+
+	prevq := uint64(0)
+	for i, val := range values {
+		if i > 0 && val < values[i-1] {
+			panic("ranksel: values must be sorted")
+		}
+
+		q := val >> uint(width)
+		for gap := q - prevq; gap > 0; {
+			size := uint64(64)
+			if gap < size {
+				size = gap
+			}
+
+			vec.Add(0, int(size))
+			gap -= size
+		}
+
+		vec.Add(1, 1)
+		rems[i] = val & mask
+		prevq = q
+	}
+
+	return vec, rems
+}
+
+// QuotientSelect returns the quotient of the ith value
+// of a sequence stored by NewQuotientVector. This is the
+// number of 0s before the ith set bit. Like Select1, i
+// starts at 1 and panics if i is zero or greater than
+// the number of set bits.
+func (v *BitVector) QuotientSelect(i int) int {
+	return v.Select1(i) - (i - 1)
+}
+
+// QuotientValue reconstructs the ith value of a sequence
+// stored by NewQuotientVector by combining its quotient with
+// the corresponding remainder. The remainders and width must
+// be the ones used to build the vector.
+func (v *BitVector) QuotientValue(i int, remainders []uint64, width int) uint64 {
+	q := uint64(v.QuotientSelect(i))
+	return (q << uint(width)) | remainders[i-1]
+}
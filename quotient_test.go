@@ -0,0 +1,43 @@
+package ranksel
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotientVector(t *testing.T) {
+	const width = 7
+
+	values := make([]uint64, 1e4)
+	val := uint64(0)
+	for i := range values {
+		val += uint64(rand.Intn(1 << 10))
+		values[i] = val
+	}
+
+	vec, rems := NewQuotientVector(values, width, nil)
+	assert.Equal(t, len(values), vec.PopCount())
+	assert.Equal(t, len(values), len(rems))
+
+	for i, val := range values {
+		q := vec.QuotientSelect(i + 1)
+		if !assert.Equal(t, int(val>>width), q) ||
+			!assert.Equal(t, val, vec.QuotientValue(i+1, rems, width)) {
+			break
+		}
+	}
+}
+
+func TestQuotientVectorLargeGap(t *testing.T) {
+	values := []uint64{0, 0, 1000, 1001, 1 << 20}
+	vec, rems := NewQuotientVector(values, 0, nil)
+
+	for i, val := range values {
+		assert.Equal(t, val, vec.QuotientValue(i+1, rems, 0))
+	}
+
+	assert.Panics(t, func() { NewQuotientVector([]uint64{2, 1}, 0, nil) })
+	assert.Panics(t, func() { NewQuotientVector(values, 64, nil) })
+}
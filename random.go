@@ -0,0 +1,37 @@
+package ranksel
+
+import (
+	"math"
+	"math/rand"
+)
+
+// NewRandomBitVector creates a vector of nbits bits where
+// each bit is set with probability density. The same seed
+// always produces the same vector. Panics if nbits is
+// negative or if density is not in range [0,1].
+func NewRandomBitVector(nbits int, density float64, seed int64, opts *Options) *BitVector {
+	if nbits < 0 {
+		panic("ranksel: bit count must not be negative")
+	} else if math.IsNaN(density) || density < 0 || density > 1 {
+		panic("ranksel: density must be in range [0,1]")
+	}
+
+	vec := NewBitVector(opts)
+	rnd := rand.New(rand.NewSource(seed))
+	for i := 0; i < nbits; i += 64 {
+		size := nbits - i
+		if size > 64 {
+			size = 64
+		}
+
+		b := uint64(0)
+		for j := 0; j < size; j++ {
+			if rnd.Float64() < density {
+				b |= 1 << uint(j)
+			}
+		}
+		vec.Add(b, size)
+	}
+
+	return vec
+}
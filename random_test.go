@@ -0,0 +1,32 @@
+package ranksel
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRandomBitVector(t *testing.T) {
+	const nbits = 1e6
+
+	for _, density := range []float64{0, 0.01, 0.3, 0.5, 1} {
+		vec := NewRandomBitVector(nbits, density, 42, nil)
+		assert.Equal(t, int(nbits), vec.Len())
+
+		actual := float64(vec.PopCount()) / nbits
+		assert.InDelta(t, density, actual, 0.005)
+
+		same := NewRandomBitVector(nbits, density, 42, nil)
+		assert.Equal(t, vec.Words(), same.Words())
+	}
+
+	vec1 := NewRandomBitVector(1000, 0.5, 1, nil)
+	vec2 := NewRandomBitVector(1000, 0.5, 2, nil)
+	assert.NotEqual(t, vec1.Words(), vec2.Words())
+
+	assert.Equal(t, 0, NewRandomBitVector(0, 0.5, 1, nil).Len())
+	assert.Panics(t, func() { NewRandomBitVector(10, math.NaN(), 1, nil) })
+	assert.Panics(t, func() { NewRandomBitVector(10, 1.5, 1, nil) })
+	assert.Panics(t, func() { NewRandomBitVector(-1, 0.5, 1, nil) })
+}
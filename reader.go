@@ -0,0 +1,55 @@
+package ranksel
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// ReadBitVectorBytes creates a vector from the first nbits bits
+// of the byte stream r. Exactly ceil(nbits/8) bytes are consumed.
+// Bit j of byte k is stored at index 8*k+j of the vector, and the
+// unused upper bits of the last byte are ignored. Returns an error
+// if r has fewer bytes than needed.
+func ReadBitVectorBytes(r io.Reader, nbits int, opts *Options) (*BitVector, error) {
+	if nbits < 0 {
+		return nil, fmt.Errorf("ranksel: invalid bit count %d", nbits)
+	}
+
+	vec := NewBitVector(opts)
+	buf := make([]byte, 4096)
+	remaining := (nbits + 7) >> 3
+	for remaining > 0 {
+		n := len(buf)
+		if remaining < n {
+			n = remaining
+		}
+
+		if _, err := io.ReadFull(r, buf[:n]); err != nil {
+			return nil, fmt.Errorf("ranksel: read failed (%v)", err)
+		}
+		remaining -= n
+
+		for i := 0; i < n; i += 8 {
+			var word uint64
+			if i+8 <= n {
+				word = binary.LittleEndian.Uint64(buf[i:])
+			} else {
+				for j := n - 1; j >= i; j-- {
+					word = (word << 8) | uint64(buf[j])
+				}
+			}
+
+			size := nbits - vec.Len()
+			if size > 64 {
+				size = 64
+			}
+			if size < 64 {
+				word &= (1 << uint(size)) - 1
+			}
+			vec.Add(word, size)
+		}
+	}
+
+	return vec, nil
+}
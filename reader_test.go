@@ -0,0 +1,55 @@
+package ranksel
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadBitVectorBytes(t *testing.T) {
+	data := make([]byte, 10007)
+	rand.Read(data)
+
+	for _, nbits := range []int{0, 1, 7, 8, 63, 64, 65, 8 * 4096, 8*len(data) - 3} {
+		r := bytes.NewReader(data)
+		vec, err := ReadBitVectorBytes(r, nbits, nil)
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		assert.Equal(t, nbits, vec.Len())
+		assert.Equal(t, len(data)-(nbits+7)/8, r.Len())
+
+		popcount := 0
+		for i := 0; i < nbits; i++ {
+			expected := uint(data[i>>3]>>uint(i&7)) & 1
+			popcount += int(expected)
+			if !assert.Equal(t, expected, vec.Bit(i)) {
+				break
+			}
+		}
+		assert.Equal(t, popcount, vec.PopCount())
+	}
+}
+
+func TestReadBitVectorBytesShort(t *testing.T) {
+	r := bytes.NewReader(make([]byte, 8))
+	_, err := ReadBitVectorBytes(r, 65, nil)
+	assert.Error(t, err)
+
+	_, err = ReadBitVectorBytes(r, -1, nil)
+	assert.Error(t, err)
+}
+
+func BenchmarkReadBitVectorBytes(b *testing.B) {
+	data := make([]byte, 1<<20)
+	rand.Read(data)
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ReadBitVectorBytes(bytes.NewReader(data), 8*len(data), nil)
+	}
+}
@@ -0,0 +1,102 @@
+package ranksel
+
+import (
+	"math"
+
+	"github.com/robskie/bit"
+)
+
+// Compressibility returns a score in range [0,1] that estimates
+// how well the vector can be compressed, where 1 means highly
+// compressible and 0 means incompressible. The score is computed
+// as
+//
+//	1 - H(p) * min(1, runs/E[runs])
+//
+// where p is the fraction of set bits, H(p) is the binary entropy
+// of p, runs is the number of runs of identical bits and E[runs]
+// = 1 + 2(n-1)p(1-p) is the expected number of runs of a random
+// vector of length n with the same density. So a vector with a
+// skewed density or with few long runs has a high score, while a
+// random vector with 50% density scores close to 0. This is only
+// a heuristic meant for choosing between representations.
+func (v *BitVector) Compressibility() float64 {
+	n := v.bits.Len()
+	if n == 0 || v.popcount == 0 || v.popcount == n {
+		return 1
+	}
+
+	p := float64(v.popcount) / float64(n)
+	h := -p*math.Log2(p) - (1-p)*math.Log2(1-p)
+
+	expected := 1 + 2*float64(n-1)*p*(1-p)
+	ratio := float64(v.runs()) / expected
+	if ratio > 1 {
+		ratio = 1
+	}
+
+	return 1 - h*ratio
+}
+
+// DensityStats returns the number of 1s and 0s, the fraction of
+// bits that are set and the average distance between consecutive
+// set bits. The density is 0 for an empty vector and the average
+// gap is 0 if there are fewer than two set bits.
+func (v *BitVector) DensityStats() (ones, zeros int, density float64, avgGap float64) {
+	ones = v.popcount
+	zeros = v.PopCount0()
+	if n := v.bits.Len(); n > 0 {
+		density = float64(ones) / float64(n)
+	}
+
+	if ones < 2 {
+		return
+	}
+
+	// The gaps add up to the distance
+	// between the first and last set bits.
+	words := v.Words()
+	first := 0
+	for k, w := range words {
+		if w != 0 {
+			first = (k << 6) + bit.Select(w, 1)
+			break
+		}
+	}
+
+	last := 0
+	for k := len(words) - 1; k >= 0; k-- {
+		if w := words[k]; w != 0 {
+			last = (k << 6) + bit.Select(w, bit.PopCount(w))
+			break
+		}
+	}
+
+	avgGap = float64(last-first) / float64(ones-1)
+	return
+}
+
+// runs returns the number of runs of identical bits.
+func (v *BitVector) runs() int {
+	n := v.bits.Len()
+	if n == 0 {
+		return 0
+	}
+
+	// Count the indices whose bit differs from the previous
+	// bit. The first bit is compared against itself.
+	words := v.Words()
+	trans := 0
+	prev := words[0] & 1
+	for k, w := range words {
+		x := w ^ ((w << 1) | prev)
+		if rem := n - (k << 6); rem < 64 {
+			x &= (1 << uint(rem)) - 1
+		}
+
+		trans += bit.PopCount(x)
+		prev = w >> 63
+	}
+
+	return trans + 1
+}
@@ -0,0 +1,90 @@
+package ranksel
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuns(t *testing.T) {
+	vec := NewBitVector(nil)
+	assert.Equal(t, 0, vec.runs())
+
+	runs := 0
+	prev := -1
+	for i := 0; i < 1e4; i++ {
+		b := 0
+		if rand.Intn(8) == 0 {
+			b = 1
+		}
+		if b != prev {
+			runs++
+			prev = b
+		}
+
+		vec.Add(uint64(b), 1)
+		if i%61 == 0 && !assert.Equal(t, runs, vec.runs()) {
+			break
+		}
+	}
+	assert.Equal(t, runs, vec.runs())
+}
+
+func TestCompressibility(t *testing.T) {
+	random := NewRandomBitVector(1e5, 0.5, 1, nil)
+	sparse := NewRandomBitVector(1e5, 0.01, 1, nil)
+
+	// Long runs with 50% density
+	runs := NewBitVector(nil)
+	for i := 0; i < 1600; i++ {
+		if (i/16)%2 == 0 {
+			runs.Add(^uint64(0), 64)
+		} else {
+			runs.Add(0, 64)
+		}
+	}
+
+	cr := random.Compressibility()
+	cs := sparse.Compressibility()
+	cl := runs.Compressibility()
+
+	assert.InDelta(t, 0, cr, 0.05)
+	assert.True(t, cs > 0.9)
+	assert.True(t, cl > 0.9)
+	assert.Equal(t, 1.0, NewBitVector(nil).Compressibility())
+	assert.Equal(t, 1.0, NewRandomBitVector(1e3, 1, 1, nil).Compressibility())
+}
+
+func TestDensityStats(t *testing.T) {
+	vec := NewBitVector(nil)
+	ones, zeros, density, avgGap := vec.DensityStats()
+	assert.Equal(t, 0, ones)
+	assert.Equal(t, 0, zeros)
+	assert.Equal(t, 0.0, density)
+	assert.Equal(t, 0.0, avgGap)
+
+	vec.AddZeros(100)
+	vec.Add(1, 1)
+	_, _, _, avgGap = vec.DensityStats()
+	assert.Equal(t, 0.0, avgGap)
+
+	vec = NewRandomBitVector(1e5+17, 0.1, 1, nil)
+	pos := []int{}
+	for i := 0; i < vec.Len(); i++ {
+		if vec.Bit(i) == 1 {
+			pos = append(pos, i)
+		}
+	}
+
+	gaps := 0
+	for i := 1; i < len(pos); i++ {
+		gaps += pos[i] - pos[i-1]
+	}
+
+	ones, zeros, density, avgGap = vec.DensityStats()
+	assert.Equal(t, len(pos), ones)
+	assert.Equal(t, vec.Len()-len(pos), zeros)
+	assert.Equal(t, float64(len(pos))/float64(vec.Len()), density)
+	assert.InDelta(t, float64(gaps)/float64(len(pos)-1), avgGap, 1e-9)
+}
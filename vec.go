@@ -4,8 +4,13 @@ package ranksel
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/gob"
 	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"sync"
 	"unsafe"
 
 	"github.com/robskie/bit"
@@ -24,12 +29,37 @@ type Options struct {
 	// This represents the number of 1s in each
 	// select sampling block. Default is 8192.
 	Ss int
+
+	// Select0Sampling enables the sampling of
+	// every Ss-th 0 which makes Select0 as fast
+	// as Select1 at the cost of more space.
+	// Default is false.
+	Select0Sampling bool
+
+	// NoSelectIndex disables the select sampling
+	// for vectors that only need rank queries.
+	// This saves space but Select1 and Select0
+	// panic if this is set. This overrides
+	// Select0Sampling. Default is false.
+	NoSelectIndex bool
 }
 
 // NewOptions creates an Options
 // object with default values.
 func NewOptions() *Options {
-	return &Options{1024, 8192}
+	return &Options{Sr: 1024, Ss: 8192}
+}
+
+// select1Sampling returns true if
+// the select samples of 1s are built.
+func (o *Options) select1Sampling() bool {
+	return !o.NoSelectIndex
+}
+
+// select0Sampling returns true if
+// the select samples of 0s are built.
+func (o *Options) select0Sampling() bool {
+	return o.Select0Sampling && !o.NoSelectIndex
 }
 
 // BitVector is a bitmap with added data structure described by G. Navarro and
@@ -37,6 +67,13 @@ func NewOptions() *Options {
 // Small, Simple Rank/Select on Bitmaps" with some minor modifications.
 //
 // See http://dcc.uchile.cl/~gnavarro/ps/sea12.1.pdf for more details.
+//
+// A BitVector is safe for concurrent use by multiple goroutines as
+// long as it is only read. Methods that modify the vector, such as
+// Add, must not be called concurrently with any other method. Use
+// Freeze to catch such modifications of a shared vector. A BitVector
+// contains a sync.Mutex, so it must not be copied by value after
+// first use; pass *BitVector instead.
 type BitVector struct {
 	bits *bit.Array
 
@@ -50,9 +87,28 @@ type BitVector struct {
 	// set bit.
 	indices []int
 
+	// indices0 is the same as indices
+	// but for 0s. This is only used if
+	// Select0Sampling is enabled.
+	indices0 []int
+
 	popcount int
 
 	opts *Options
+
+	// frozen is set by Freeze
+	frozen bool
+
+	// complement caches the vector returned by
+	// Complement for Select0ViaComplement. It is
+	// discarded whenever the vector is modified.
+	// Since it is lazily built by a read method,
+	// read methods must hold mu to access it.
+	// Methods that modify the vector may access
+	// it directly since they already must not run
+	// concurrently with any other method.
+	complement *BitVector
+	mu         sync.Mutex
 }
 
 // NewBitVector creates a new BitVector.
@@ -63,49 +119,302 @@ func NewBitVector(opts *Options) *BitVector {
 
 	b := bit.NewArray(0)
 	rs := make([]int, 1)
-	idx := make([]int, 1)
+
+	var idx []int
+	if opts.select1Sampling() {
+		idx = make([]int, 1)
+	}
+
+	var idx0 []int
+	if opts.select0Sampling() {
+		idx0 = make([]int, 1)
+	}
 
 	return &BitVector{
-		bits:    b,
-		ranks:   rs,
-		indices: idx,
-		opts:    opts,
+		bits:     b,
+		ranks:    rs,
+		indices:  idx,
+		indices0: idx0,
+		opts:     opts,
 	}
 }
 
 // Add appends the bits given its size to the vector.
+// Bits above the given size are ignored.
 func (v *BitVector) Add(bits uint64, size int) {
+	v.checkFrozen()
 	if size <= 0 || size > 64 {
 		panic("ranksel: bit size must be in range [1,64]")
+	} else if size < 64 {
+		bits &= (1 << uint(size)) - 1
 	}
 
 	// Add bits
+	v.complement = nil
 	v.bits.Add(bits, size)
-	vlength := v.bits.Len()
+	v.updateSamples(bits, size, v.bits.Len())
+}
+
+// AddZeros appends n 0s to the vector.
+func (v *BitVector) AddZeros(n int) {
+	v.addRun(0, n)
+}
+
+// AddOnes appends n 1s to the vector.
+func (v *BitVector) AddOnes(n int) {
+	v.addRun(^uint64(0), n)
+}
+
+// addRun appends n copies of the bit in b which must be
+// either 0 or all 1s. Since the bits are identical, the
+// samples are directly computed instead of being updated
+// after each word.
+func (v *BitVector) addRun(b uint64, n int) {
+	v.checkFrozen()
+	if n < 0 {
+		panic("ranksel: run length must not be negative")
+	} else if n == 0 {
+		return
+	}
+
+	v.complement = nil
+	start := v.bits.Len()
+	end := start + n
+	for vlength := start; vlength < end; {
+		// Fill up the current word first so
+		// that the next words are aligned.
+		size := 64 - (vlength & 63)
+		if size > end-vlength {
+			size = end - vlength
+		}
+
+		if size < 64 {
+			v.bits.Add(b&((1<<uint(size))-1), size)
+		} else {
+			v.bits.Add(b, 64)
+		}
+		vlength += size
+	}
+
+	popcount := v.popcount
+	popcount0 := start - popcount
+	if b != 0 {
+		v.popcount += n
+	}
+
+	// Update rank sampling
+	for k := len(v.ranks); k*v.opts.Sr < end; k++ {
+		rank := popcount
+		if b != 0 {
+			rank += k*v.opts.Sr - start
+		}
+		v.ranks = append(v.ranks, rank)
+	}
+
+	// Update select sampling
+	if b != 0 && v.opts.select1Sampling() {
+		for k := len(v.indices); k*v.opts.Ss < v.popcount; k++ {
+			// Index of the (k*ss)+1th set bit
+			idx := start + k*v.opts.Ss - popcount
+			v.indices = append(v.indices, idx & ^0x3F)
+		}
+	} else if v.opts.select0Sampling() {
+		for k := len(v.indices0); k*v.opts.Ss < popcount0+n; k++ {
+			// Index of the (k*ss)+1th 0
+			idx := start + k*v.opts.Ss - popcount0
+			v.indices0 = append(v.indices0, idx & ^0x3F)
+		}
+	}
+}
 
+// updateSamples updates the popcount and the rank and
+// select sampling after appending bits of the given
+// size which made the vector length equal to vlength.
+func (v *BitVector) updateSamples(bits uint64, size, vlength int) {
 	// Increment popcount
 	popcnt := bit.PopCount(bits)
 	v.popcount += popcnt
 
-	// Update rank sampling
-	lenranks := len(v.ranks)
-	overflow := vlength - (lenranks * v.opts.Sr)
-	if overflow > 0 {
-		v.ranks = append(v.ranks, 0)
-
+	// Update rank sampling. Bits longer than
+	// Sr can contain more than one sample.
+	overflow := vlength - (len(v.ranks) * v.opts.Sr)
+	for ; overflow > 0; overflow -= v.opts.Sr {
 		rank := bit.Rank(bits, size-overflow-1)
-		v.ranks[lenranks] = v.popcount - popcnt + rank
+		v.ranks = append(v.ranks, v.popcount-popcnt+rank)
 	}
 
 	// Update select sampling
-	lenidx := len(v.indices)
-	overflow = v.popcount - (lenidx * v.opts.Ss)
-	if overflow > 0 {
-		v.indices = append(v.indices, 0)
+	if v.opts.select1Sampling() {
+		overflow = v.popcount - (len(v.indices) * v.opts.Ss)
+		for ; overflow > 0; overflow -= v.opts.Ss {
+			sel := bit.Select(bits, popcnt-overflow+1)
+			v.indices = append(v.indices, (vlength-size+sel) & ^0x3F)
+		}
+	}
+
+	// Update select sampling of 0s
+	if v.opts.select0Sampling() {
+		popcnt0 := size - popcnt
+		overflow = (vlength - v.popcount) - (len(v.indices0) * v.opts.Ss)
+		for ; overflow > 0; overflow -= v.opts.Ss {
+			sel := bit.Select(^bits, popcnt0-overflow+1)
+			v.indices0 = append(v.indices0, (vlength-size+sel) & ^0x3F)
+		}
+	}
+}
+
+// RebuildSamples discards the popcount and the rank and
+// select samples and recomputes them from the stored bits.
+// This can be used to repair a vector whose samples are
+// stale or corrupted, such as one decoded from an older
+// or damaged encoding.
+func (v *BitVector) RebuildSamples() {
+	v.checkFrozen()
+	v.complement = nil
+	v.ranks = make([]int, 1)
+	v.indices = nil
+	if v.opts.select1Sampling() {
+		v.indices = make([]int, 1)
+	}
+	v.indices0 = nil
+	if v.opts.select0Sampling() {
+		v.indices0 = make([]int, 1)
+	}
+	v.popcount = 0
+
+	length := v.bits.Len()
+	vbits := v.bits.Bits()
+	for i := 0; i < length; i += 64 {
+		size := length - i
+		if size > 64 {
+			size = 64
+		}
+
+		b := vbits[i>>6]
+		if size < 64 {
+			b &= (1 << uint(size)) - 1
+		}
+		v.updateSamples(b, size, i+size)
+	}
+}
+
+// Verify checks that the popcount and the rank and select
+// samples are consistent with the stored bits. It returns an
+// error describing the first mismatch found, or nil if there
+// is none. This is useful for validating decoded vectors.
+func (v *BitVector) Verify() error {
+	s := &BitVector{bits: v.bits, opts: v.opts}
+	s.RebuildSamples()
 
-		sel := bit.Select(bits, popcnt-overflow+1)
-		v.indices[lenidx] = (vlength - size + sel) & ^0x3F
+	if s.popcount != v.popcount {
+		return fmt.Errorf("ranksel: popcount mismatch (expected %d, stored %d)",
+			s.popcount, v.popcount)
 	}
+
+	return checkErr(
+		verifySamples("rank", s.ranks, v.ranks),
+		verifySamples("select", s.indices, v.indices),
+		verifySamples("select 0", s.indices0, v.indices0),
+	)
+}
+
+// verifySamples compares the expected samples with the
+// stored ones and returns an error at the first mismatch.
+func verifySamples(name string, expected, stored []int) error {
+	if len(expected) != len(stored) {
+		return fmt.Errorf("ranksel: %s sample count mismatch (expected %d, stored %d)",
+			name, len(expected), len(stored))
+	}
+
+	for i, e := range expected {
+		if e != stored[i] {
+			return fmt.Errorf("ranksel: %s sample %d mismatch (expected %d, stored %d)",
+				name, i, e, stored[i])
+		}
+	}
+
+	return nil
+}
+
+// Compact reallocates the rank and select samples so
+// that they use no more memory than needed. This also
+// discards the cached complement used by
+// Select0ViaComplement. It is useful for long-lived
+// vectors that were built with many calls to Add. The
+// storage of the bits is not shrunk since bit.Array
+// cannot be rebuilt from existing words.
+func (v *BitVector) Compact() {
+	v.checkFrozen()
+	v.mu.Lock()
+	v.complement = nil
+	v.mu.Unlock()
+
+	ranks := make([]int, len(v.ranks))
+	copy(ranks, v.ranks)
+	v.ranks = ranks
+
+	if v.indices != nil {
+		indices := make([]int, len(v.indices))
+		copy(indices, v.indices)
+		v.indices = indices
+	}
+
+	if v.indices0 != nil {
+		indices0 := make([]int, len(v.indices0))
+		copy(indices0, v.indices0)
+		v.indices0 = indices0
+	}
+}
+
+// Freeze makes the vector immutable. Any method that
+// modifies the vector, such as Add, panics after the
+// vector is frozen. Calling Freeze more than once has
+// no effect.
+func (v *BitVector) Freeze() {
+	v.frozen = true
+}
+
+// IsFrozen returns true if Freeze has been called.
+func (v *BitVector) IsFrozen() bool {
+	return v.frozen
+}
+
+func (v *BitVector) checkFrozen() {
+	if v.frozen {
+		panic("ranksel: modification of frozen BitVector")
+	}
+}
+
+// SubVector returns a new vector that contains a copy of the
+// bits in the interval [lo, hi) of this vector. The new vector
+// has its own samples built with the same options, so its
+// Rank1(k) is equal to PopCountRange(lo, lo+k+1) of this vector.
+// Panics if the interval is not inside the vector.
+func (v *BitVector) SubVector(lo, hi int) *BitVector {
+	if lo < 0 || hi > v.bits.Len() || lo > hi {
+		panic("ranksel: invalid range")
+	}
+
+	opts := *v.opts
+	sub := NewBitVector(&opts)
+	words := v.Words()
+	for i := lo; i < hi; i += 64 {
+		k := i >> 6
+		shift := uint(i & 63)
+
+		b := words[k] >> shift
+		if shift != 0 && k+1 < len(words) {
+			b |= words[k+1] << (64 - shift)
+		}
+
+		size := hi - i
+		if size > 64 {
+			size = 64
+		}
+		sub.Add(b, size)
+	}
+
+	return sub
 }
 
 // Get returns the uint64 representation of
@@ -150,15 +459,110 @@ func (v *BitVector) Rank1(i int) int {
 
 // Rank0 counts the number of 0s from
 // the beginning up to the ith index.
+// Like Rank1, the ith index is included
+// so Rank1(i) + Rank0(i) is always i+1.
 func (v *BitVector) Rank0(i int) int {
 	return i - v.Rank1(i) + 1
 }
 
+// PopCountRange counts the number of 1s in the interval
+// [i, j). This only scans the words inside the interval and
+// does not use the rank sampling, so it is fastest for short
+// intervals and for word aligned endpoints where no masking
+// is needed. Panics if the interval is not inside the vector.
+func (v *BitVector) PopCountRange(i, j int) int {
+	if i < 0 || j > v.bits.Len() || i > j {
+		panic("ranksel: invalid range")
+	} else if i == j {
+		return 0
+	}
+
+	lo := i >> 6
+	hi := j >> 6
+	vbits := v.bits.Bits()
+	if lo == hi {
+		b := vbits[lo] >> uint(i&63)
+		return bit.PopCount(b & ((1 << uint(j-i)) - 1))
+	}
+
+	count := bit.PopCount(vbits[lo] >> uint(i&63))
+	for _, b := range vbits[lo+1 : hi] {
+		count += bit.PopCount(b)
+	}
+
+	if j&63 != 0 {
+		count += bit.PopCount(vbits[hi] & ((1 << uint(j&63)) - 1))
+	}
+
+	return count
+}
+
+// RankDifferences returns the number of 1s between each
+// pair of consecutive cut points. The jth element is equal
+// to Rank1(cutpoints[j+1]) - Rank1(cutpoints[j]), that is,
+// the number of 1s in the interval (cutpoints[j],
+// cutpoints[j+1]]. The cut points must be sorted in
+// increasing order so that they can be processed in a
+// single forward pass.
+func (v *BitVector) RankDifferences(cutpoints []int) []int {
+	if len(cutpoints) < 2 {
+		return []int{}
+	}
+
+	diffs := make([]int, len(cutpoints)-1)
+	cur := v.newRankCursor()
+	prev := cur.rank1(cutpoints[0])
+	for j, c := range cutpoints[1:] {
+		if c < cutpoints[j] {
+			panic("ranksel: cut points must be sorted")
+		}
+
+		rank := cur.rank1(c)
+		diffs[j] = rank - prev
+		prev = rank
+	}
+
+	return diffs
+}
+
+// RankMany returns Rank1 of each of the given indices. The
+// indices can be in any order, but if they are sorted in
+// increasing order then each query continues the scan of
+// the previous one, which is faster than calling Rank1
+// for each index when the indices are close together.
+func (v *BitVector) RankMany(positions []int) []int {
+	ranks := make([]int, len(positions))
+
+	sorted := true
+	for j := 1; j < len(positions); j++ {
+		if positions[j] < positions[j-1] {
+			sorted = false
+			break
+		}
+	}
+
+	if !sorted {
+		for j, i := range positions {
+			ranks[j] = v.Rank1(i)
+		}
+		return ranks
+	}
+
+	cur := v.newRankCursor()
+	for j, i := range positions {
+		ranks[j] = cur.rank1(i)
+	}
+
+	return ranks
+}
+
 // Select1 returns the index of the ith set bit.
 // Panics if i is zero or greater than the number
-// of set bits.
+// of set bits, or if NoSelectIndex is set.
 func (v *BitVector) Select1(i int) int {
-	if i > v.popcount {
+	if v.opts.NoSelectIndex {
+		panic("ranksel: select index is disabled")
+	} else if i > v.popcount {
 		panic("ranksel: input exceeds number of 1s")
 	} else if i == 0 {
 		panic("ranksel: input must be greater than 0")
@@ -198,16 +602,125 @@ func (v *BitVector) Select1(i int) int {
 	return idx
 }
 
+// SelectPair returns the indices of the ith and (i+1)th set
+// bits. This is the same as calling Select1(i) and Select1(i+1)
+// but the second index is found by scanning forward from the
+// first. nextPos is -1 if the ith set bit is the last one.
+// Panics if i is zero or greater than the number of set bits.
+func (v *BitVector) SelectPair(i int) (pos, nextPos int) {
+	pos = v.Select1(i)
+	if i == v.popcount {
+		return pos, -1
+	}
+
+	vbits := v.bits.Bits()
+	k := (pos + 1) >> 6
+	b := vbits[k] & (^uint64(0) << uint((pos+1)&63))
+	for b == 0 {
+		k++
+		b = vbits[k]
+	}
+
+	return pos, (k << 6) + bit.Select(b, 1)
+}
+
+// Quantile returns the index of the set bit at fraction
+// p of all the set bits. It uses the nearest-rank method:
+// the returned bit is the kth set bit where k is the
+// smallest integer such that k >= p*PopCount(), clamped
+// to [1, PopCount()]. So p = 0 returns the first set bit,
+// p = 1 returns the last set bit and p = 0.5 on a vector
+// with 10 set bits returns the 5th. Panics if p is NaN or
+// outside [0,1], or if the vector has no set bits.
+func (v *BitVector) Quantile(p float64) int {
+	if math.IsNaN(p) || p < 0 || p > 1 {
+		panic("ranksel: quantile must be in range [0,1]")
+	} else if v.popcount == 0 {
+		panic("ranksel: vector has no 1s")
+	}
+
+	// Undo rounding errors of p*n that
+	// push the product past an integer.
+	n := float64(v.popcount)
+	k := int(math.Ceil(p * n))
+	if k > 1 && float64(k-1)/n >= p {
+		k--
+	}
+
+	if k < 1 {
+		k = 1
+	} else if k > v.popcount {
+		k = v.popcount
+	}
+
+	return v.Select1(k)
+}
+
 // Select0 returns the index of the ith zero. Panics
-// if i is zero or greater than the number of zeroes.
-// This is slower than Select1 in most cases.
+// if i is zero or greater than the number of zeroes,
+// or if NoSelectIndex is set. This is slower than
+// Select1 in most cases unless Select0Sampling is
+// enabled.
 func (v *BitVector) Select0(i int) int {
-	if i > (v.bits.Len() - v.popcount) {
+	if v.opts.NoSelectIndex {
+		panic("ranksel: select index is disabled")
+	} else if i > v.PopCount0() {
 		panic("ranksel: input exceeds number of 0s")
 	} else if i == 0 {
 		panic("ranksel: input must be greater than 0")
 	}
 
+	imin := 0
+	if v.opts.select0Sampling() {
+		imin = v.select0Block(i)
+	} else {
+		imin = v.select0Search(i)
+	}
+
+	idx := 0
+	vbits := v.bits.Bits()
+	aidx := (imin * v.opts.Sr) >> 6
+	rank0 := (imin * v.opts.Sr) - v.ranks[imin]
+	for ii, b := range vbits[aidx:] {
+		b = ^b
+		rank0 += bit.PopCount(b)
+
+		if rank0 >= i {
+			overflow := rank0 - i
+			popcnt := bit.PopCount(b)
+
+			idx = (aidx + ii) << 6
+			idx += bit.Select(b, popcnt-overflow)
+
+			break
+		}
+	}
+
+	return idx
+}
+
+// select0Block returns the index of a rank
+// sampling block that precedes the ith zero
+// using the select samples of 0s.
+func (v *BitVector) select0Block(i int) int {
+	j := (i - 1) / v.opts.Ss
+	k := v.indices0[j] / v.opts.Sr
+
+	for k+1 < len(v.ranks) {
+		r0 := ((k + 1) * v.opts.Sr) - v.ranks[k+1]
+		if r0 >= i {
+			break
+		}
+		k++
+	}
+
+	return k
+}
+
+// select0Search returns the index of a rank
+// sampling block that precedes the ith zero
+// using a binary search on the rank samples.
+func (v *BitVector) select0Search(i int) int {
 	// Do a binary search on the rank samples to find
 	// the largest rank sample that is less than i.
 	// From https://en.wikipedia.org/wiki/Binary_search_algorithm
@@ -225,28 +738,160 @@ func (v *BitVector) Select0(i int) int {
 	}
 	imin--
 
-	idx := 0
-	vbits := v.bits.Bits()
-	aidx := (imin * v.opts.Sr) >> 6
-	rank0 := (imin * v.opts.Sr) - v.ranks[imin]
-	for ii, b := range vbits[aidx:] {
-		b = ^b
-		rank0 += bit.PopCount(b)
+	return imin
+}
 
-		if rank0 >= i {
-			overflow := rank0 - i
-			popcnt := bit.PopCount(b)
+// Complement returns a new vector of the same
+// length and options whose bits are the
+// inverse of the bits of this vector.
+func (v *BitVector) Complement() *BitVector {
+	opts := *v.opts
+	return v.invert(&opts)
+}
 
-			idx = (aidx + ii) << 6
-			idx += bit.Select(b, popcnt-overflow)
+// invert returns the complement of this
+// vector built with the given options.
+func (v *BitVector) invert(opts *Options) *BitVector {
+	c := NewBitVector(opts)
 
-			break
+	length := v.bits.Len()
+	vbits := v.bits.Bits()
+	for i := 0; i < length; i += 64 {
+		size := length - i
+		if size > 64 {
+			size = 64
 		}
+
+		b := ^vbits[i>>6]
+		if size < 64 {
+			b &= (1 << uint(size)) - 1
+		}
+		c.Add(b, size)
+	}
+
+	return c
+}
+
+// Select0ViaComplement returns the index of the ith
+// zero by calling Select1 on the complement of this
+// vector. The complement is built on the first call
+// and reused until the vector is modified, so this
+// trades the size of a second vector for Select0
+// queries that are as fast as Select1. Panics if i
+// is zero or greater than the number of zeroes, or
+// if NoSelectIndex is set.
+func (v *BitVector) Select0ViaComplement(i int) int {
+	if v.opts.NoSelectIndex {
+		panic("ranksel: select index is disabled")
+	}
+
+	v.mu.Lock()
+	if v.complement == nil {
+		// Only Select1 is used on the complement
+		opts := *v.opts
+		opts.Select0Sampling = false
+		v.complement = v.invert(&opts)
+	}
+	c := v.complement
+	v.mu.Unlock()
+
+	if i > c.popcount {
+		panic("ranksel: input exceeds number of 0s")
+	}
+	return c.Select1(i)
+}
+
+// rankCursor answers Rank1 queries at non-decreasing
+// indices by resuming the word scan from the previous
+// query instead of starting from a rank sample.
+type rankCursor struct {
+	v *BitVector
+
+	// rank is the number of 1s
+	// in the words before widx.
+	rank int
+	widx int
+}
+
+func (v *BitVector) newRankCursor() *rankCursor {
+	return &rankCursor{v: v}
+}
+
+// rank1 returns Rank1(i). The index i must not
+// be less than the index of the previous query.
+func (c *rankCursor) rank1(i int) int {
+	v := c.v
+	if i >= v.bits.Len() || i < 0 {
+		panic("ranksel: index out of range")
 	}
 
+	// Skip to the nearest rank sample if
+	// it is ahead of the current word.
+	j := i / v.opts.Sr
+	if ip := (j * v.opts.Sr) >> 6; ip > c.widx {
+		c.widx = ip
+		c.rank = v.ranks[j]
+	}
+
+	bidx := i >> 6
+	vbits := v.bits.Bits()
+	for ; c.widx < bidx; c.widx++ {
+		c.rank += bit.PopCount(vbits[c.widx])
+	}
+
+	return c.rank + bit.Rank(vbits[bidx], i&63)
+}
+
+// Select1Range returns the index of the ith set bit in
+// the interval [lo, hi), or -1 if the interval has fewer
+// than i set bits. Panics if i is zero or if the interval
+// is not inside the vector.
+func (v *BitVector) Select1Range(i, lo, hi int) int {
+	base := v.rangeBase(i, lo, hi)
+	if base+i > v.popcount {
+		return -1
+	}
+
+	idx := v.Select1(base + i)
+	if idx >= hi {
+		return -1
+	}
 	return idx
 }
 
+// Select0Range returns the index of the ith zero in
+// the interval [lo, hi), or -1 if the interval has
+// fewer than i zeroes. Panics if i is zero or if the
+// interval is not inside the vector.
+func (v *BitVector) Select0Range(i, lo, hi int) int {
+	base := lo - v.rangeBase(i, lo, hi)
+	if base+i > v.PopCount0() {
+		return -1
+	}
+
+	idx := v.Select0(base + i)
+	if idx >= hi {
+		return -1
+	}
+	return idx
+}
+
+// rangeBase validates the arguments of Select1Range
+// and Select0Range and returns the number of 1s
+// before index lo.
+func (v *BitVector) rangeBase(i, lo, hi int) int {
+	if i == 0 {
+		panic("ranksel: input must be greater than 0")
+	} else if lo < 0 || hi > v.bits.Len() || lo > hi {
+		panic("ranksel: invalid range")
+	}
+
+	if lo == 0 {
+		return 0
+	}
+	return v.Rank1(lo - 1)
+}
+
 func checkErr(err ...error) error {
 	for _, e := range err {
 		if e != nil {
@@ -268,6 +913,7 @@ func (v *BitVector) GobEncode() ([]byte, error) {
 		enc.Encode(v.indices),
 		enc.Encode(v.popcount),
 		enc.Encode(v.opts),
+		enc.Encode(v.indices0),
 	)
 
 	if err != nil {
@@ -279,11 +925,13 @@ func (v *BitVector) GobEncode() ([]byte, error) {
 
 // GobDecode populates this vector from gob streams.
 func (v *BitVector) GobDecode(data []byte) error {
+	v.checkFrozen()
 	buf := bytes.NewReader(data)
 	dec := gob.NewDecoder(buf)
 
 	v.opts = NewOptions()
 	v.bits = bit.NewArray(0)
+	v.complement = nil
 	err := checkErr(
 		dec.Decode(v.bits),
 		dec.Decode(&v.ranks),
@@ -292,6 +940,14 @@ func (v *BitVector) GobDecode(data []byte) error {
 		dec.Decode(v.opts),
 	)
 
+	// Older encodings have no select samples of 0s
+	if err == nil {
+		v.indices0 = nil
+		if err = dec.Decode(&v.indices0); err == io.EOF {
+			err = nil
+		}
+	}
+
 	if err != nil {
 		err = fmt.Errorf("ranksel: decode failed (%v)", err)
 	}
@@ -299,6 +955,65 @@ func (v *BitVector) GobDecode(data []byte) error {
 	return err
 }
 
+// Words returns the words that hold the bits of the vector.
+// Index i of the vector is stored in bit i%64 of word i/64,
+// and the bits after index Len()-1 in the last word are zero.
+// The returned slice aliases the internal storage of the
+// vector so it must not be modified, and it may no longer
+// reflect the vector after more bits are added. Use
+// AppendWords to get a copy instead.
+func (v *BitVector) Words() []uint64 {
+	return v.bits.Bits()[:(v.bits.Len()+63)>>6]
+}
+
+// AppendWords appends a copy of the words returned
+// by Words to dst and returns the extended slice.
+func (v *BitVector) AppendWords(dst []uint64) []uint64 {
+	return append(dst, v.Words()...)
+}
+
+// Checksum returns a 64-bit FNV-1a hash of the length and bits
+// of the vector. It does not depend on the Options used, so two
+// vectors with the same bits have the same checksum. This can be
+// used to detect corrupted data but not deliberate tampering.
+func (v *BitVector) Checksum() uint64 {
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+
+	length := v.bits.Len()
+	binary.LittleEndian.PutUint64(buf, uint64(length))
+	h.Write(buf)
+
+	for i, w := range v.Words() {
+		if rem := length - (i << 6); rem < 64 {
+			w &= (1 << uint(rem)) - 1
+		}
+
+		binary.LittleEndian.PutUint64(buf, w)
+		h.Write(buf)
+	}
+
+	return h.Sum64()
+}
+
+// SelectSampleSpacing returns the distance in bits between
+// consecutive select samples. Each sample marks the word that
+// contains every Ss-th set bit, so a large spacing means that
+// the region is sparse and Select1 has more words to scan,
+// while a small spacing means that the region is dense.
+func (v *BitVector) SelectSampleSpacing() []int {
+	if len(v.indices) == 0 {
+		return []int{}
+	}
+
+	spacing := make([]int, len(v.indices)-1)
+	for i := range spacing {
+		spacing[i] = v.indices[i+1] - v.indices[i]
+	}
+
+	return spacing
+}
+
 // Len returns the number of bits stored.
 func (v *BitVector) Len() int {
 	return v.bits.Len()
@@ -309,6 +1024,11 @@ func (v *BitVector) PopCount() int {
 	return v.popcount
 }
 
+// PopCount0 returns the total number of 0s.
+func (v *BitVector) PopCount0() int {
+	return v.bits.Len() - v.popcount
+}
+
 // Size returns the vector size in bytes.
 func (v *BitVector) Size() int {
 	sizeofInt := int(unsafe.Sizeof(int(0)))
@@ -316,6 +1036,7 @@ func (v *BitVector) Size() int {
 	size := v.bits.Size()
 	size += len(v.ranks) * sizeofInt
 	size += len(v.indices) * sizeofInt
+	size += len(v.indices0) * sizeofInt
 
 	return size
 }
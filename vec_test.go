@@ -1,8 +1,13 @@
 package ranksel
 
 import (
+	"bytes"
+	"encoding/gob"
 	"fmt"
+	"math"
 	"math/rand"
+	"sort"
+	"sync"
 	"testing"
 
 	"github.com/robskie/bit"
@@ -21,6 +26,94 @@ func TestBit(t *testing.T) {
 	}
 }
 
+func TestAddMasksBits(t *testing.T) {
+	vec := NewBitVector(nil)
+	vec.Add(^uint64(0), 3)
+	vec.Add(0, 1)
+	assert.Equal(t, 3, vec.PopCount())
+	assert.Equal(t, []uint64{0x7}, vec.Words())
+}
+
+func TestWords(t *testing.T) {
+	vec := NewBitVector(nil)
+	assert.Equal(t, []uint64{}, vec.Words())
+
+	words := []uint64{}
+	for i := 0; i < 100; i++ {
+		b := uint64(rand.Int63())
+		vec.Add(b, 64)
+		words = append(words, b)
+	}
+	vec.Add(0x5, 3)
+	words = append(words, 0x5)
+
+	assert.Equal(t, words, vec.Words())
+
+	dst := make([]uint64, 1, 200)
+	dst = vec.AppendWords(dst)
+	assert.Equal(t, append([]uint64{0}, words...), dst)
+}
+
+func TestAddRun(t *testing.T) {
+	opts := &Options{Sr: 1024, Ss: 8192, Select0Sampling: true}
+	vec := NewBitVector(opts)
+	expected := NewBitVector(opts)
+	for i := 0; i < 1e3; i++ {
+		n := rand.Intn(5000)
+		if rand.Intn(4) == 0 {
+			n = rand.Intn(70)
+		}
+
+		b := rand.Intn(2)
+		if b == 1 {
+			vec.AddOnes(n)
+		} else {
+			vec.AddZeros(n)
+		}
+
+		for j := 0; j < n; j++ {
+			expected.Add(uint64(b), 1)
+		}
+	}
+
+	assert.Equal(t, expected.Len(), vec.Len())
+	assert.Equal(t, expected.popcount, vec.popcount)
+	assert.Equal(t, expected.ranks, vec.ranks)
+	assert.Equal(t, expected.indices, vec.indices)
+	assert.Equal(t, expected.indices0, vec.indices0)
+	assert.Equal(t, expected.Words(), vec.Words())
+
+	vec.AddZeros(0)
+	assert.Equal(t, expected.Len(), vec.Len())
+	assert.Panics(t, func() { vec.AddOnes(-1) })
+}
+
+func TestFreeze(t *testing.T) {
+	vec := NewRandomBitVector(1e4, 0.5, 1, nil)
+	assert.False(t, vec.IsFrozen())
+
+	vec.Freeze()
+	vec.Freeze()
+	assert.True(t, vec.IsFrozen())
+
+	data, _ := vec.GobEncode()
+	assert.Panics(t, func() { vec.Add(1, 1) })
+	assert.Panics(t, func() { vec.AddZeros(1) })
+	assert.Panics(t, func() { vec.AddOnes(1) })
+	assert.Panics(t, func() { vec.RebuildSamples() })
+	assert.Panics(t, func() { vec.Compact() })
+	assert.Panics(t, func() { vec.GobDecode(data) })
+	assert.Panics(t, func() { vec.UnmarshalJSON([]byte("{}")) })
+	assert.Equal(t, 10000, vec.Len())
+
+	// Reads still work
+	assert.NotPanics(t, func() {
+		vec.Rank1(100)
+		vec.Select1(1)
+		vec.Select0ViaComplement(1)
+	})
+}
+
 func TestRank(t *testing.T) {
 	vec := NewBitVector(nil)
 	ranks1 := make([]int, 1e6)
@@ -55,6 +148,129 @@ func TestRank(t *testing.T) {
 	}
 }
 
+func TestPopCountRange(t *testing.T) {
+	vec := NewBitVector(nil)
+	for i := 0; i < 1e4; i++ {
+		vec.Add(uint64(rand.Intn(2)), 1)
+	}
+
+	rank := func(i int) int {
+		if i == 0 {
+			return 0
+		}
+		return vec.Rank1(i - 1)
+	}
+
+	for k := 0; k < 1e4; k++ {
+		i := rand.Intn(vec.Len() + 1)
+		j := i + rand.Intn(vec.Len()-i+1)
+		if k%4 == 0 {
+			i &= ^0x3F
+			j &= ^0x3F
+		}
+
+		if !assert.Equal(t, rank(j)-rank(i), vec.PopCountRange(i, j)) {
+			break
+		}
+	}
+
+	assert.Equal(t, vec.PopCount(), vec.PopCountRange(0, vec.Len()))
+	assert.Panics(t, func() { vec.PopCountRange(2, 1) })
+	assert.Panics(t, func() { vec.PopCountRange(0, vec.Len()+1) })
+}
+
+func TestRankDifferences(t *testing.T) {
+	vec := NewBitVector(nil)
+	for i := 0; i < 1e5; i++ {
+		vec.Add(uint64(rand.Intn(2)), 1)
+	}
+
+	cutpoints := []int{0}
+	for c := 0; c < vec.Len(); {
+		cutpoints = append(cutpoints, c)
+		c += rand.Intn(3000)
+	}
+	cutpoints = append(cutpoints, vec.Len()-1)
+
+	diffs := vec.RankDifferences(cutpoints)
+	if assert.Equal(t, len(cutpoints)-1, len(diffs)) {
+		for j, d := range diffs {
+			expected := vec.Rank1(cutpoints[j+1]) - vec.Rank1(cutpoints[j])
+			if !assert.Equal(t, expected, d) {
+				break
+			}
+		}
+	}
+
+	assert.Equal(t, []int{}, vec.RankDifferences([]int{5}))
+	assert.Panics(t, func() { vec.RankDifferences([]int{5, 4}) })
+}
+
+func TestRankEdges(t *testing.T) {
+	vec := NewBitVector(nil)
+	vec.Add(0, 1)
+	vec.AddOnes(1023)
+	vec.AddZeros(1024)
+	vec.Add(1, 1)
+
+	cases := []struct{ i, rank1, rank0 int }{
+		{0, 0, 1},
+		{1, 1, 1},
+		{1022, 1022, 1},
+		{1023, 1023, 1},
+		{1024, 1023, 2},
+		{2047, 1023, 1025},
+		{2048, 1024, 1025},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.rank1, vec.Rank1(c.i), fmt.Sprint("Rank1 at ", c.i))
+		assert.Equal(t, c.rank0, vec.Rank0(c.i), fmt.Sprint("Rank0 at ", c.i))
+	}
+
+	for i := 0; i < vec.Len(); i++ {
+		if !assert.Equal(t, i+1, vec.Rank1(i)+vec.Rank0(i)) {
+			break
+		}
+	}
+
+	last := vec.Len() - 1
+	assert.Equal(t, vec.PopCount(), vec.Rank1(last))
+	assert.Equal(t, vec.PopCount0(), vec.Rank0(last))
+	assert.Equal(t, 1025, vec.PopCount0())
+	assert.Equal(t, vec.Len(), vec.PopCount()+vec.PopCount0())
+}
+
+func TestRankMany(t *testing.T) {
+	vec := NewRandomBitVector(1e5, 0.5, 1, nil)
+
+	positions := make([]int, 1e3)
+	for i := range positions {
+		positions[i] = rand.Intn(vec.Len())
+	}
+
+	check := func() {
+		ranks := vec.RankMany(positions)
+		if assert.Equal(t, len(positions), len(ranks)) {
+			for j, i := range positions {
+				if !assert.Equal(t, vec.Rank1(i), ranks[j]) {
+					break
+				}
+			}
+		}
+	}
+
+	// Unsorted
+	check()
+
+	// Sorted
+	sort.Ints(positions)
+	check()
+
+	assert.Equal(t, []int{}, vec.RankMany(nil))
+	assert.Panics(t, func() { vec.RankMany([]int{0, vec.Len()}) })
+}
+
 // TestRankSparse tests rank queries on sparse
 // array (few 1s). A sparse bit array results in
 // duplicate values in the rank sampling.
@@ -115,6 +331,133 @@ func TestSelect(t *testing.T) {
 	}
 }
 
+func TestSelectPair(t *testing.T) {
+	for _, density := range []float64{0.001, 0.5} {
+		vec := NewRandomBitVector(1e5, density, 1, nil)
+		for i := 1; i < vec.PopCount(); i++ {
+			pos, next := vec.SelectPair(i)
+			if !assert.Equal(t, vec.Select1(i), pos) ||
+				!assert.Equal(t, vec.Select1(i+1), next) {
+				break
+			}
+		}
+
+		pos, next := vec.SelectPair(vec.PopCount())
+		assert.Equal(t, vec.Select1(vec.PopCount()), pos)
+		assert.Equal(t, -1, next)
+	}
+
+	vec := NewBitVector(nil)
+	vec.Add(1, 64)
+	vec.Add(1, 1)
+	pos, next := vec.SelectPair(1)
+	assert.Equal(t, 0, pos)
+	assert.Equal(t, 64, next)
+	assert.Panics(t, func() { vec.SelectPair(0) })
+	assert.Panics(t, func() { vec.SelectPair(3) })
+}
+
+func TestQuantile(t *testing.T) {
+	vec := NewBitVector(nil)
+	for i := 0; i < 100; i++ {
+		if i%10 == 3 {
+			vec.Add(1, 1)
+		} else {
+			vec.Add(0, 1)
+		}
+	}
+
+	// Set bits are at 3, 13, ..., 93
+	assert.Equal(t, 3, vec.Quantile(0))
+	assert.Equal(t, 3, vec.Quantile(0.1))
+	assert.Equal(t, 13, vec.Quantile(0.11))
+	assert.Equal(t, 43, vec.Quantile(0.5))
+	assert.Equal(t, 93, vec.Quantile(0.95))
+	assert.Equal(t, 93, vec.Quantile(1))
+
+	// p*PopCount() is not exact in float64
+	assert.Equal(t, 63, vec.Quantile(0.7))
+
+	vec = NewBitVector(nil)
+	vec.AddOnes(100)
+	for _, k := range []int{7, 14, 28, 55, 70} {
+		assert.Equal(t, k-1, vec.Quantile(float64(k)/100))
+	}
+	for k := 1; k <= 100; k++ {
+		if !assert.Equal(t, k-1, vec.Quantile(float64(k)/100)) {
+			break
+		}
+	}
+	assert.Equal(t, 6, vec.Quantile(0.07))
+
+	assert.Panics(t, func() { vec.Quantile(math.NaN()) })
+	assert.Panics(t, func() { vec.Quantile(-0.1) })
+	assert.Panics(t, func() { vec.Quantile(1.1) })
+	assert.Panics(t, func() { NewBitVector(nil).Quantile(0.5) })
+}
+
+func TestSelectRange(t *testing.T) {
+	vec := NewBitVector(nil)
+	for i := 0; i < 1e5; i++ {
+		vec.Add(uint64(rand.Intn(2)), 1)
+	}
+
+	for k := 0; k < 1e3; k++ {
+		lo := rand.Intn(vec.Len() + 1)
+		hi := lo + rand.Intn(vec.Len()-lo+1)
+		i := rand.Intn(hi-lo+2) + 1
+
+		// Find expected indices
+		sel1, sel0 := -1, -1
+		n1, n0 := 0, 0
+		for j := lo; j < hi; j++ {
+			if vec.Bit(j) == 1 {
+				n1++
+				if n1 == i {
+					sel1 = j
+				}
+			} else {
+				n0++
+				if n0 == i {
+					sel0 = j
+				}
+			}
+		}
+
+		if !assert.Equal(t, sel1, vec.Select1Range(i, lo, hi)) ||
+			!assert.Equal(t, sel0, vec.Select0Range(i, lo, hi)) {
+			break
+		}
+	}
+
+	assert.Panics(t, func() { vec.Select1Range(0, 0, 10) })
+	assert.Panics(t, func() { vec.Select1Range(1, 10, 5) })
+	assert.Panics(t, func() { vec.Select0Range(1, 0, vec.Len()+1) })
+}
+
+func TestSelect0Sampling(t *testing.T) {
+	opts := &Options{Sr: 1024, Ss: 8192, Select0Sampling: true}
+
+	for _, density := range []int{2, 32, 1024} {
+		vec := NewBitVector(opts)
+		sel0 := []int{}
+		for i := 0; i < 1e6; i++ {
+			if rand.Intn(density) == 0 {
+				vec.Add(0, 1)
+				sel0 = append(sel0, i)
+			} else {
+				vec.Add(1, 1)
+			}
+		}
+
+		for i, idx := range sel0 {
+			if !assert.Equal(t, idx, vec.Select0(i+1)) {
+				break
+			}
+		}
+	}
+}
+
 func TestSelect1Sparse(t *testing.T) {
 	const sr = 1024
 
@@ -161,6 +504,99 @@ func TestSelect0Sparse(t *testing.T) {
 	}
 }
 
+func TestComplement(t *testing.T) {
+	vec := NewBitVector(nil)
+	for i := 0; i < 1e4+7; i++ {
+		vec.Add(uint64(rand.Intn(2)), 1)
+	}
+
+	comp := vec.Complement()
+	assert.Equal(t, vec.opts, comp.opts)
+	assert.False(t, vec.opts == comp.opts)
+	assert.Equal(t, vec.Len(), comp.Len())
+	assert.Equal(t, vec.Len()-vec.PopCount(), comp.PopCount())
+	for i := 0; i < vec.Len(); i++ {
+		if !assert.Equal(t, vec.Bit(i)^1, comp.Bit(i)) {
+			break
+		}
+	}
+}
+
+func TestSelect0ViaComplement(t *testing.T) {
+	vec := NewBitVector(nil)
+	sel0 := []int{}
+
+	for i := 0; i < 1e6; i++ {
+		bit := rand.Intn(2)
+		vec.Add(uint64(bit), 1)
+
+		if bit == 0 {
+			sel0 = append(sel0, i)
+		}
+	}
+
+	for i, idx := range sel0 {
+		if !assert.Equal(t, idx, vec.Select0ViaComplement(i+1)) {
+			break
+		}
+	}
+
+	// Adding bits must invalidate the cached complement
+	vec.Add(0, 1)
+	sel0 = append(sel0, vec.Len()-1)
+	assert.Equal(t, vec.Len()-1, vec.Select0ViaComplement(len(sel0)))
+
+	// The cached complement needs no select samples of 0s
+	opts := &Options{Sr: 1024, Ss: 8192, Select0Sampling: true}
+	vec = NewRandomBitVector(1e5, 0.5, 1, opts)
+	vec.Select0ViaComplement(1)
+	assert.Nil(t, vec.complement.indices0)
+	assert.True(t, opts.Select0Sampling)
+}
+
+// TestConcurrentReads runs read queries from several
+// goroutines. Run with -race to detect shared writes.
+func TestConcurrentReads(t *testing.T) {
+	vec := NewBitVector(nil)
+	sel1 := []int{}
+	sel0 := []int{}
+	for i := 0; i < 1e5; i++ {
+		bit := rand.Intn(2)
+		vec.Add(uint64(bit), 1)
+
+		if bit == 1 {
+			sel1 = append(sel1, i)
+		} else {
+			sel0 = append(sel0, i)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+
+			rnd := rand.New(rand.NewSource(seed))
+			for k := 0; k < 1e3; k++ {
+				i := rnd.Intn(len(sel1))
+				j := rnd.Intn(len(sel0))
+
+				ok := assert.Equal(t, sel1[i], vec.Select1(i+1)) &&
+					assert.Equal(t, sel0[j], vec.Select0(j+1)) &&
+					assert.Equal(t, sel0[j], vec.Select0ViaComplement(j+1)) &&
+					assert.Equal(t, i+1, vec.Rank1(sel1[i])) &&
+					assert.Equal(t, j+1, vec.Rank0(sel0[j])) &&
+					assert.EqualValues(t, 1, vec.Bit(sel1[i]))
+				if !ok {
+					return
+				}
+			}
+		}(int64(g))
+	}
+	wg.Wait()
+}
+
 func TestEncodeDecode(t *testing.T) {
 	vec := NewBitVector(nil)
 	for i := 0; i < 1e3; i++ {
@@ -178,6 +614,397 @@ func TestEncodeDecode(t *testing.T) {
 	assert.Equal(t, vec.indices, nvec.indices)
 	assert.Equal(t, vec.popcount, nvec.popcount)
 	assert.Equal(t, vec.opts, nvec.opts)
+
+	opts := &Options{Sr: 1024, Ss: 8192, Select0Sampling: true}
+	vec = NewRandomBitVector(1e5, 0.5, 1, opts)
+	data, _ = vec.GobEncode()
+	nvec = NewBitVector(nil)
+	nvec.GobDecode(data)
+
+	assert.Equal(t, vec.indices0, nvec.indices0)
+	assert.Equal(t, vec.opts, nvec.opts)
+}
+
+func TestDecodeOldEncoding(t *testing.T) {
+	// Options before Select0Sampling was added
+	type options struct {
+		Sr int
+		Ss int
+	}
+
+	opts := &Options{Sr: 512, Ss: 1024}
+	vec := NewRandomBitVector(1e5, 0.3, 1, opts)
+
+	buf := &bytes.Buffer{}
+	enc := gob.NewEncoder(buf)
+	err := checkErr(
+		enc.Encode(vec.bits),
+		enc.Encode(vec.ranks),
+		enc.Encode(vec.indices),
+		enc.Encode(vec.popcount),
+		enc.Encode(options{opts.Sr, opts.Ss}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	nvec := NewBitVector(nil)
+	if !assert.NoError(t, nvec.GobDecode(buf.Bytes())) {
+		return
+	}
+
+	assert.NoError(t, nvec.Verify())
+	assert.Nil(t, nvec.indices0)
+	assert.Equal(t, opts, nvec.opts)
+	assert.Equal(t, vec.Words(), nvec.Words())
+
+	for i := 0; i < vec.Len(); i += 97 {
+		if !assert.Equal(t, vec.Rank1(i), nvec.Rank1(i)) {
+			break
+		}
+	}
+	for i := 1; i <= vec.PopCount(); i += 101 {
+		if !assert.Equal(t, vec.Select1(i), nvec.Select1(i)) {
+			break
+		}
+	}
+	for i := 1; i <= vec.PopCount0(); i += 103 {
+		if !assert.Equal(t, vec.Select0(i), nvec.Select0(i)) {
+			break
+		}
+	}
+}
+
+func TestGobStream(t *testing.T) {
+	type message struct {
+		Vec   *BitVector
+		Iface interface{}
+	}
+	gob.Register(&BitVector{})
+
+	opts := &Options{Sr: 512, Ss: 2048}
+	vec := NewBitVector(opts)
+	for i := 0; i < 1e4; i++ {
+		vec.Add(uint64(rand.Int63()), 64)
+	}
+
+	buf := &bytes.Buffer{}
+	err := gob.NewEncoder(buf).Encode(message{vec, vec})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	msg := message{}
+	err = gob.NewDecoder(buf).Decode(&msg)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for _, nvec := range []*BitVector{msg.Vec, msg.Iface.(*BitVector)} {
+		assert.Equal(t, vec.Words(), nvec.Words())
+		assert.Equal(t, opts, nvec.opts)
+
+		for i := 1; i <= vec.PopCount(); i += 101 {
+			if !assert.Equal(t, vec.Select1(i), nvec.Select1(i)) {
+				break
+			}
+		}
+	}
+}
+
+func TestRebuildSamples(t *testing.T) {
+	vec := NewBitVector(&Options{Sr: 1024, Ss: 8192, Select0Sampling: true})
+	for i := 0; i < 1e4; i++ {
+		b := uint64(rand.Int63())
+		vec.Add(b, bit.Size(b))
+	}
+
+	ranks := append([]int{}, vec.ranks...)
+	indices := append([]int{}, vec.indices...)
+	indices0 := append([]int{}, vec.indices0...)
+	popcount := vec.popcount
+
+	// Corrupt samples
+	for i := range vec.ranks {
+		vec.ranks[i] = rand.Int()
+	}
+	vec.indices = vec.indices[:1]
+	vec.indices0 = nil
+	vec.popcount = 0
+
+	vec.RebuildSamples()
+	assert.Equal(t, ranks, vec.ranks)
+	assert.Equal(t, indices, vec.indices)
+	assert.Equal(t, indices0, vec.indices0)
+	assert.Equal(t, popcount, vec.popcount)
+
+	for i := 1; i <= vec.PopCount(); i += 97 {
+		idx := vec.Select1(i)
+		if !assert.Equal(t, i, vec.Rank1(idx)) {
+			break
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	opts := &Options{Sr: 1024, Ss: 8192, Select0Sampling: true}
+	vec := NewBitVector(opts)
+	for i := 0; i < 1e4; i++ {
+		b := uint64(rand.Int63())
+		vec.Add(b, bit.Size(b))
+	}
+	vec.AddOnes(1e4)
+	vec.AddZeros(1e4)
+	vec.Freeze()
+	assert.NoError(t, vec.Verify())
+
+	data, _ := vec.GobEncode()
+	corrupt := func(f func(v *BitVector)) *BitVector {
+		nvec := NewBitVector(nil)
+		nvec.GobDecode(data)
+		f(nvec)
+		return nvec
+	}
+
+	err := corrupt(func(v *BitVector) { v.popcount++ }).Verify()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "popcount")
+	}
+
+	err = corrupt(func(v *BitVector) { v.ranks[5]++ }).Verify()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "rank sample 5 mismatch")
+	}
+
+	err = corrupt(func(v *BitVector) { v.indices[2] += 64 }).Verify()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "select sample 2 mismatch")
+	}
+
+	err = corrupt(func(v *BitVector) { v.indices0 = v.indices0[:1] }).Verify()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "select 0 sample count mismatch")
+	}
+
+	// Sampling blocks smaller than a word
+	vec = NewBitVector(&Options{Sr: 64, Ss: 16, Select0Sampling: true})
+	for i := 0; i < 1e4; i++ {
+		vec.Add(uint64(rand.Intn(2)), 1)
+	}
+	assert.NoError(t, vec.Verify())
+
+	vec = NewBitVector(&Options{Sr: 64, Ss: 16})
+	vec.AddOnes(200)
+	assert.NoError(t, vec.Verify())
+}
+
+func TestCompact(t *testing.T) {
+	vec := NewBitVector(&Options{Sr: 64, Ss: 64})
+	for i := 0; i < 1e4; i++ {
+		b := uint64(rand.Int63())
+		vec.Add(b, bit.Size(b))
+	}
+
+	words := vec.AppendWords(nil)
+	ranks := append([]int{}, vec.ranks...)
+	indices := append([]int{}, vec.indices...)
+	sel := make([]int, vec.PopCount())
+	for i := range sel {
+		sel[i] = vec.Select1(i + 1)
+	}
+
+	vec.Compact()
+	assert.Equal(t, len(vec.ranks), cap(vec.ranks))
+	assert.Equal(t, len(vec.indices), cap(vec.indices))
+	assert.Equal(t, ranks, vec.ranks)
+	assert.Equal(t, indices, vec.indices)
+	assert.Equal(t, words, vec.Words())
+
+	for i, idx := range sel {
+		if !assert.Equal(t, idx, vec.Select1(i+1)) ||
+			!assert.Equal(t, i+1, vec.Rank1(idx)) {
+			break
+		}
+	}
+
+	// Vector must still be usable after compacting
+	vec.Add(1, 1)
+	assert.Equal(t, len(sel)+1, vec.PopCount())
+	assert.Equal(t, vec.Len()-1, vec.Select1(vec.PopCount()))
+}
+
+func TestSubVector(t *testing.T) {
+	vec := NewRandomBitVector(1e5, 0.5, 1, nil)
+
+	for n := 0; n < 50; n++ {
+		lo := rand.Intn(vec.Len() + 1)
+		hi := lo + rand.Intn(vec.Len()-lo+1)
+		if n == 0 {
+			lo, hi = 0, vec.Len()
+		}
+
+		sub := vec.SubVector(lo, hi)
+		assert.Equal(t, hi-lo, sub.Len())
+		assert.Equal(t, vec.PopCountRange(lo, hi), sub.PopCount())
+		assert.Equal(t, vec.opts, sub.opts)
+
+		for k := 0; k < sub.Len(); k++ {
+			if !assert.Equal(t, vec.Bit(lo+k), sub.Bit(k)) ||
+				!assert.Equal(t, vec.PopCountRange(lo, lo+k+1), sub.Rank1(k)) {
+				break
+			}
+		}
+	}
+
+	assert.Equal(t, 0, vec.SubVector(10, 10).Len())
+	assert.Panics(t, func() { vec.SubVector(-1, 10) })
+	assert.Panics(t, func() { vec.SubVector(10, 5) })
+	assert.Panics(t, func() { vec.SubVector(0, vec.Len()+1) })
+}
+
+func TestChecksum(t *testing.T) {
+	vec1 := NewBitVector(nil)
+	vec2 := NewBitVector(&Options{Sr: 512, Ss: 1024})
+	for i := 0; i < 1e4; i++ {
+		b := uint64(rand.Int63())
+		vec1.Add(b, 64)
+		vec2.Add(b, 64)
+	}
+	assert.Equal(t, vec1.Checksum(), vec2.Checksum())
+
+	vec1.Add(1, 1)
+	assert.NotEqual(t, vec1.Checksum(), vec2.Checksum())
+
+	// Length contributes to the checksum
+	vec2.Add(1, 1)
+	vec1.Add(0, 1)
+	vec2.Add(0, 2)
+	assert.NotEqual(t, vec1.Checksum(), vec2.Checksum())
+
+	assert.Equal(t, NewBitVector(nil).Checksum(), NewBitVector(nil).Checksum())
+}
+
+func TestSelectSampleSpacing(t *testing.T) {
+	const ss = 1024
+
+	// Dense first half and sparse second half
+	vec := NewBitVector(&Options{Sr: 1024, Ss: ss})
+	for i := 0; i < 1e5; i++ {
+		vec.Add(uint64(rand.Intn(2)), 1)
+	}
+	for i := 0; i < 1e6; i++ {
+		if rand.Intn(32) == 0 {
+			vec.Add(1, 1)
+		} else {
+			vec.Add(0, 1)
+		}
+	}
+
+	spacing := vec.SelectSampleSpacing()
+	assert.Equal(t, len(vec.indices)-1, len(spacing))
+
+	pos := 0
+	for i, sp := range spacing {
+		pos += sp
+		if !assert.Equal(t, vec.indices[i+1], pos) ||
+			!assert.Equal(t, vec.Select1((i+1)*ss+1)&^0x3F, pos) {
+			break
+		}
+	}
+
+	assert.True(t, spacing[0] < spacing[len(spacing)-1])
+	assert.Equal(t, []int{}, NewBitVector(nil).SelectSampleSpacing())
+}
+
+func TestNoSelectIndex(t *testing.T) {
+	opts := NewOptions()
+	opts.NoSelectIndex = true
+	opts.Select0Sampling = true
+
+	vec := NewBitVector(opts)
+	expected := NewBitVector(nil)
+	for i := 0; i < 1e4; i++ {
+		b := uint64(rand.Int63())
+		vec.Add(b, 64)
+		expected.Add(b, 64)
+	}
+	vec.AddOnes(1e4)
+	expected.AddOnes(1e4)
+	vec.AddZeros(1e4)
+	expected.AddZeros(1e4)
+
+	assert.Nil(t, vec.indices)
+	assert.Nil(t, vec.indices0)
+	assert.Equal(t, expected.ranks, vec.ranks)
+	assert.True(t, vec.Size() < expected.Size())
+	assert.Equal(t, []int{}, vec.SelectSampleSpacing())
+
+	for i := 0; i < vec.Len(); i += 13 {
+		if !assert.Equal(t, expected.Rank1(i), vec.Rank1(i)) {
+			break
+		}
+	}
+
+	assert.Panics(t, func() { vec.Select1(1) })
+	assert.Panics(t, func() { vec.Select0(1) })
+	assert.Panics(t, func() { vec.Select0ViaComplement(1) })
+	assert.Nil(t, vec.complement)
+
+	vec.RebuildSamples()
+	assert.Nil(t, vec.indices)
+	assert.Equal(t, expected.ranks, vec.ranks)
+
+	data, err := vec.GobEncode()
+	assert.NoError(t, err)
+	nvec := NewBitVector(nil)
+	assert.NoError(t, nvec.GobDecode(data))
+	assert.Equal(t, opts, nvec.opts)
+	assert.Equal(t, 0, len(nvec.indices))
+	assert.Panics(t, func() { nvec.Select1(1) })
+}
+
+// TestRebuildSamplesSmall tests sampling block
+// sizes that are smaller than a word so that a
+// word contains more than one sample.
+func TestRebuildSamplesSmall(t *testing.T) {
+	opts := &Options{Sr: 64, Ss: 16, Select0Sampling: true}
+
+	vec := NewBitVector(opts)
+	vec.AddOnes(200)
+	vec.AddZeros(200)
+	for i := 0; i < 1e3; i++ {
+		vec.Add(uint64(rand.Int63()), 64)
+	}
+
+	ranks := append([]int{}, vec.ranks...)
+	indices := append([]int{}, vec.indices...)
+	indices0 := append([]int{}, vec.indices0...)
+
+	vec.RebuildSamples()
+	assert.Equal(t, ranks, vec.ranks)
+	assert.Equal(t, indices, vec.indices)
+	assert.Equal(t, indices0, vec.indices0)
+	assert.Equal(t, 199, vec.Select1(200))
+	assert.Equal(t, 399, vec.Select0(200))
+
+	// Samples built by adding whole words
+	wvec := NewBitVector(opts)
+	for i, w := range vec.Words() {
+		size := vec.Len() - i*64
+		if size > 64 {
+			size = 64
+		}
+		wvec.Add(w, size)
+	}
+	assert.Equal(t, ranks, wvec.ranks)
+	assert.Equal(t, indices, wvec.indices)
+	assert.Equal(t, indices0, wvec.indices0)
+
+	// Gob round trip keeps the samples
+	data, _ := vec.GobEncode()
+	nvec := NewBitVector(nil)
+	assert.NoError(t, nvec.GobDecode(data))
+	assert.Equal(t, indices, nvec.indices)
 }
 
 func TestOverhead(t *testing.T) {
@@ -191,9 +1018,22 @@ func TestOverhead(t *testing.T) {
 	percentage := (overhead / rawsize) * 100
 
 	fmt.Printf("=== OVERHEAD: %.2f%%\n", percentage)
+
+	opts := NewOptions()
+	opts.NoSelectIndex = true
+	vec = NewBitVector(opts)
+	for i := 0; i < 1e6; i++ {
+		vec.Add(^uint64(0), 64)
+	}
+
+	overhead = float64(vec.Size()) - rawsize
+	percentage = (overhead / rawsize) * 100
+
+	fmt.Printf("=== OVERHEAD (NoSelectIndex): %.2f%%\n", percentage)
 }
 
 var bigVector *BitVector
+var bigVector0 *BitVector
 
 func initBigVector() {
 	if bigVector == nil {
@@ -205,6 +1045,42 @@ func initBigVector() {
 	}
 }
 
+func initBigVector0() {
+	if bigVector0 == nil {
+		size := 1 << 28
+		opts := NewOptions()
+		opts.Select0Sampling = true
+		bigVector0 = NewBitVector(opts)
+		for i := 0; i < size/64; i++ {
+			bigVector0.Add(uint64(rand.Int63()), 64)
+		}
+	}
+}
+
+func BenchmarkAddZeros(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		vec := NewBitVector(nil)
+		for j := 0; j < 1e3; j++ {
+			vec.AddZeros(1e4)
+			vec.Add(1, 1)
+		}
+	}
+}
+
+func BenchmarkAddZerosLoop(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		vec := NewBitVector(nil)
+		for j := 0; j < 1e3; j++ {
+			n := int(1e4)
+			for ; n >= 64; n -= 64 {
+				vec.Add(0, 64)
+			}
+			vec.Add(0, n)
+			vec.Add(1, 1)
+		}
+	}
+}
+
 func BenchmarkRank1(b *testing.B) {
 	initBigVector()
 
@@ -219,6 +1095,34 @@ func BenchmarkRank1(b *testing.B) {
 	}
 }
 
+func BenchmarkRankMany(b *testing.B) {
+	initBigVector()
+
+	idx := make([]int, b.N)
+	for i := range idx {
+		idx[i] = rand.Intn(bigVector.Len())
+	}
+	sort.Ints(idx)
+
+	b.ResetTimer()
+	bigVector.RankMany(idx)
+}
+
+func BenchmarkRankManyRank1(b *testing.B) {
+	initBigVector()
+
+	idx := make([]int, b.N)
+	for i := range idx {
+		idx[i] = rand.Intn(bigVector.Len())
+	}
+	sort.Ints(idx)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bigVector.Rank1(idx[i])
+	}
+}
+
 func BenchmarkRank0(b *testing.B) {
 	initBigVector()
 
@@ -233,6 +1137,34 @@ func BenchmarkRank0(b *testing.B) {
 	}
 }
 
+func BenchmarkPopCountRange(b *testing.B) {
+	initBigVector()
+
+	idx := make([]int, b.N)
+	for i := range idx {
+		idx[i] = rand.Intn(bigVector.Len()-4096) & ^0x3F
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bigVector.PopCountRange(idx[i], idx[i]+4096)
+	}
+}
+
+func BenchmarkPopCountRangeRank1(b *testing.B) {
+	initBigVector()
+
+	idx := make([]int, b.N)
+	for i := range idx {
+		idx[i] = (rand.Intn(bigVector.Len()-4096) & ^0x3F) + 64
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = bigVector.Rank1(idx[i]+4095) - bigVector.Rank1(idx[i]-1)
+	}
+}
+
 func BenchmarkSelect1(b *testing.B) {
 	initBigVector()
 
@@ -262,17 +1194,41 @@ func BenchmarkSelect0(b *testing.B) {
 	}
 }
 
+func BenchmarkSelect0Sampling(b *testing.B) {
+	initBigVector0()
+
+	in := make([]int, b.N)
+	popcnt0 := bigVector0.Len() - bigVector0.PopCount()
+	for i := range in {
+		in[i] = rand.Intn(popcnt0) + 1
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bigVector0.Select0(in[i])
+	}
+}
+
+func BenchmarkSelect0ViaComplement(b *testing.B) {
+	initBigVector()
+
+	in := make([]int, b.N)
+	popcnt0 := bigVector.Len() - bigVector.PopCount()
+	for i := range in {
+		in[i] = rand.Intn(popcnt0) + 1
+	}
+
+	bigVector.Select0ViaComplement(1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bigVector.Select0ViaComplement(in[i])
+	}
+}
+
 func BenchmarkSelect1D3(b *testing.B) {
 	// Create vector with 3% bit density
-	vec := NewBitVector(nil)
-	for i := 0; i < 1e7; i++ {
-		v := rand.Intn(33)
-		if v == 1 {
-			vec.Add(1, 1)
-		} else {
-			vec.Add(0, 1)
-		}
-	}
+	vec := NewRandomBitVector(1e7, 1.0/33, 1, nil)
 
 	in := make([]int, b.N)
 	for i := range in {
@@ -287,15 +1243,7 @@ func BenchmarkSelect1D3(b *testing.B) {
 
 func BenchmarkSelect0D3(b *testing.B) {
 	// Create vector with 3% bit density
-	vec := NewBitVector(nil)
-	for i := 0; i < 1e7; i++ {
-		v := rand.Intn(33)
-		if v == 1 {
-			vec.Add(1, 1)
-		} else {
-			vec.Add(0, 1)
-		}
-	}
+	vec := NewRandomBitVector(1e7, 1.0/33, 1, nil)
 
 	in := make([]int, b.N)
 	popcnt0 := vec.Len() - vec.PopCount()
@@ -311,15 +1259,7 @@ func BenchmarkSelect0D3(b *testing.B) {
 
 func BenchmarkSelect1D2(b *testing.B) {
 	// Create vector with 2% bit density
-	vec := NewBitVector(nil)
-	for i := 0; i < 1e7; i++ {
-		v := rand.Intn(50)
-		if v == 1 {
-			vec.Add(1, 1)
-		} else {
-			vec.Add(0, 1)
-		}
-	}
+	vec := NewRandomBitVector(1e7, 1.0/50, 1, nil)
 
 	in := make([]int, b.N)
 	for i := range in {
@@ -334,15 +1274,7 @@ func BenchmarkSelect1D2(b *testing.B) {
 
 func BenchmarkSelect0D2(b *testing.B) {
 	// Create vector with 2% bit density
-	vec := NewBitVector(nil)
-	for i := 0; i < 1e7; i++ {
-		v := rand.Intn(50)
-		if v == 1 {
-			vec.Add(1, 1)
-		} else {
-			vec.Add(0, 1)
-		}
-	}
+	vec := NewRandomBitVector(1e7, 1.0/50, 1, nil)
 
 	in := make([]int, b.N)
 	popcnt0 := vec.Len() - vec.PopCount()
@@ -358,15 +1290,7 @@ func BenchmarkSelect0D2(b *testing.B) {
 
 func BenchmarkSelect1D1(b *testing.B) {
 	// Create vector with 1% bit density
-	vec := NewBitVector(nil)
-	for i := 0; i < 1e7; i++ {
-		v := rand.Intn(100)
-		if v == 1 {
-			vec.Add(1, 1)
-		} else {
-			vec.Add(0, 1)
-		}
-	}
+	vec := NewRandomBitVector(1e7, 1.0/100, 1, nil)
 
 	in := make([]int, b.N)
 	for i := range in {
@@ -381,15 +1305,7 @@ func BenchmarkSelect1D1(b *testing.B) {
 
 func BenchmarkSelect0D1(b *testing.B) {
 	// Create vector with 1% bit density
-	vec := NewBitVector(nil)
-	for i := 0; i < 1e7; i++ {
-		v := rand.Intn(100)
-		if v == 1 {
-			vec.Add(1, 1)
-		} else {
-			vec.Add(0, 1)
-		}
-	}
+	vec := NewRandomBitVector(1e7, 1.0/100, 1, nil)
 
 	in := make([]int, b.N)
 	popcnt0 := vec.Len() - vec.PopCount()